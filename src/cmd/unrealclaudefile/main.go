@@ -0,0 +1,25 @@
+// Command unrealclaudefile exposes the unreal-index service to
+// tooling other than Claude Code's hooks.
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/Joxx0r/UnrealClaudeFileHelper/internal/lsp"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		fmt.Fprintln(os.Stderr, "usage: unrealclaudefile <command>")
+		os.Exit(1)
+	}
+
+	switch os.Args[1] {
+	case "lsp":
+		lsp.Run()
+	default:
+		fmt.Fprintf(os.Stderr, "unknown command %q\n", os.Args[1])
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+// ── Zoekt-style query DSL for Grep interception ──────────────
+//
+// Recognizes a compact query language in the Grep `pattern` field:
+// file:<glob>, lang:<id>, sym:<name>, class:<name>, func:<name>,
+// -file:<glob> (negation), case:yes|no, and free-text terms combined
+// implicitly with AND. Quoted phrases stay literal.
+
+// queryFieldRe matches a single `-?field:value` token.
+var queryFieldRe = regexp.MustCompile(`^(-)?(file|lang|sym|class|func|case):(.+)$`)
+
+// looksLikeQuery reports whether pattern uses at least one DSL field
+// operator, so plain patterns keep going through the existing
+// classDefRe/uePrefixRe/funcDefRe rule-based routing untouched.
+var queryOperatorRe = regexp.MustCompile(`(?:^|\s)-?(?:file|lang|sym|class|func|case):\S`)
+
+func looksLikeQuery(pattern string) bool {
+	return queryOperatorRe.MatchString(pattern)
+}
+
+// AtomNode is a single `field:value` term (or a bare word/phrase,
+// which is parsed as field "text").
+type AtomNode struct {
+	Field string
+	Value string
+}
+
+// NotNode negates a single atom, e.g. -file:<glob>.
+type NotNode struct {
+	Child AtomNode
+}
+
+// AndNode is the root of the query: every child is implicitly ANDed.
+type AndNode struct {
+	Children []interface{} // AtomNode or NotNode
+}
+
+// tokenizeQuery splits on whitespace, keeping quoted phrases intact.
+func tokenizeQuery(s string) []string {
+	var tokens []string
+	var cur strings.Builder
+	inQuotes := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"':
+			inQuotes = !inQuotes
+			cur.WriteByte(c)
+		case c == ' ' && !inQuotes:
+			if cur.Len() > 0 {
+				tokens = append(tokens, cur.String())
+				cur.Reset()
+			}
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	if cur.Len() > 0 {
+		tokens = append(tokens, cur.String())
+	}
+	return tokens
+}
+
+// parseQuery is a small recursive-descent parser: query := term*.
+// Malformed input (an empty field value, unbalanced quotes) reports ok=false.
+func parseQuery(pattern string) (AndNode, bool) {
+	tokens := tokenizeQuery(pattern)
+	if len(tokens) == 0 || strings.Count(pattern, `"`)%2 != 0 {
+		return AndNode{}, false
+	}
+
+	var ast AndNode
+	for _, tok := range tokens {
+		if m := queryFieldRe.FindStringSubmatch(tok); m != nil {
+			negate, field, value := m[1] == "-", m[2], strings.Trim(m[3], `"`)
+			if value == "" {
+				return AndNode{}, false
+			}
+			atom := AtomNode{Field: field, Value: value}
+			if negate {
+				ast.Children = append(ast.Children, NotNode{Child: atom})
+			} else {
+				ast.Children = append(ast.Children, atom)
+			}
+			continue
+		}
+
+		value := strings.Trim(tok, `"`)
+		if value == "" {
+			return AndNode{}, false
+		}
+		ast.Children = append(ast.Children, AtomNode{Field: "text", Value: value})
+	}
+	return ast, true
+}
+
+// handleQueryDSL recognizes and dispatches a DSL query in pattern. It
+// returns false when pattern doesn't use any DSL operators, so the
+// caller can fall back to the existing regex-based smart routing.
+// Once a query is recognized it always terminates the process, via
+// either denyStructured (a routed result) or allow (malformed input
+// or a precise query with no results).
+func handleQueryDSL(ctx context.Context, pattern string) bool {
+	if !looksLikeQuery(pattern) {
+		return false
+	}
+
+	ast, ok := parseQuery(pattern)
+	if !ok {
+		allow()
+	}
+
+	var classSym, funcs, text, fileGlobs, excludeGlobs []string
+	var lang, caseOpt string
+
+	for _, child := range ast.Children {
+		switch n := child.(type) {
+		case AtomNode:
+			switch n.Field {
+			case "class", "sym":
+				classSym = append(classSym, n.Value)
+			case "func":
+				funcs = append(funcs, n.Value)
+			case "file":
+				fileGlobs = append(fileGlobs, n.Value)
+			case "lang":
+				lang = n.Value
+			case "case":
+				caseOpt = n.Value
+			case "text":
+				text = append(text, n.Value)
+			}
+		case NotNode:
+			if n.Child.Field == "file" {
+				excludeGlobs = append(excludeGlobs, n.Child.Value)
+			}
+		}
+	}
+
+	if len(classSym) == 1 && len(funcs) == 0 {
+		dispatchQueryFindType(ctx, classSym[0], fileGlobs)
+	}
+	if len(funcs) == 1 && len(classSym) == 0 {
+		dispatchQueryFindMember(ctx, funcs[0], fileGlobs)
+	}
+	dispatchQueryGrep(ctx, append(append([]string{}, text...), append(classSym, funcs...)...), fileGlobs, excludeGlobs, lang, caseOpt)
+	return true
+}
+
+func dispatchQueryFindType(ctx context.Context, name string, fileGlobs []string) {
+	p := url.Values{}
+	p.Set("name", name)
+	p.Set("maxResults", "20")
+	if len(fileGlobs) > 0 {
+		p.Set("pathGlob", fileGlobs[0])
+	}
+
+	var data FindTypeResponse
+	if !client.FetchJSON(ctx, "/find-type", p, &data) || data.Error != "" || len(data.Results) == 0 {
+		allow()
+	}
+
+	var lines []string
+	for _, r := range data.Results {
+		lines = append(lines, fmt.Sprintf("%s:%d: %s %s (%s)", r.Path, r.Line, r.Kind, r.Name, r.Project))
+	}
+	denyStructured("PreToolUse", data.Results, fmt.Sprintf(
+		"[unreal-index] Query-routed to /find-type for \"class:%s\":\n\n%s\n\n"+
+			"Precise type definition results from index.",
+		name, strings.Join(lines, "\n")))
+}
+
+func dispatchQueryFindMember(ctx context.Context, name string, fileGlobs []string) {
+	p := url.Values{}
+	p.Set("name", name)
+	p.Set("maxResults", "20")
+	if len(fileGlobs) > 0 {
+		p.Set("pathGlob", fileGlobs[0])
+	}
+
+	var data FindMemberResponse
+	if !client.FetchJSON(ctx, "/find-member", p, &data) || data.Error != "" || len(data.Results) == 0 {
+		allow()
+	}
+
+	var lines []string
+	for _, r := range data.Results {
+		owner := r.OwnerName
+		if owner == "" {
+			owner = "(global)"
+		}
+		lines = append(lines, fmt.Sprintf("%s:%d: %s %s::%s", r.Path, r.Line, r.Kind, owner, r.Name))
+	}
+	denyStructured("PreToolUse", data.Results, fmt.Sprintf(
+		"[unreal-index] Query-routed to /find-member for \"func:%s\":\n\n%s\n\n"+
+			"Precise member definition results from index.",
+		name, strings.Join(lines, "\n")))
+}
+
+func dispatchQueryGrep(ctx context.Context, terms, fileGlobs, excludeGlobs []string, lang, caseOpt string) {
+	pattern := strings.Join(terms, " ")
+	if pattern == "" {
+		allow()
+	}
+
+	p := url.Values{}
+	p.Set("pattern", pattern)
+	p.Set("maxResults", "30")
+	p.Set("grouped", "false")
+	p.Set("symbols", "false")
+	if len(fileGlobs) > 0 {
+		p.Set("pathGlob", fileGlobs[0])
+	}
+	if len(excludeGlobs) > 0 {
+		p.Set("excludeGlob", excludeGlobs[0])
+	}
+	if lang != "" {
+		p.Set("language", lang)
+	}
+	if caseOpt != "" {
+		p.Set("caseSensitive", fmt.Sprintf("%t", caseOpt == "yes"))
+	}
+
+	var data GrepResponse
+	if !client.FetchJSON(ctx, "/grep", p, &data) || data.Error != "" || len(data.Results) == 0 {
+		allow()
+	}
+
+	var lines []string
+	for _, r := range data.Results {
+		lines = append(lines, fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match))
+	}
+	trunc := ""
+	if data.Truncated {
+		trunc = fmt.Sprintf(" (%d of %d)", len(data.Results), data.TotalMatches)
+	}
+	denyStructured("PreToolUse", data.Results, fmt.Sprintf(
+		"[unreal-index] Query-routed to /grep for \"%s\"%s:\n\n%s\n\n"+
+			"Results from pre-built index.",
+		pattern, trunc, strings.Join(lines, "\n")))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTokenizeQuery(t *testing.T) {
+	cases := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"foo", []string{"foo"}},
+		{"file:*.cpp lang:cpp", []string{"file:*.cpp", "lang:cpp"}},
+		{`sym:"My Class"`, []string{`sym:"My Class"`}},
+		{`"two words" bar`, []string{`"two words"`, "bar"}},
+		{"  a   b  ", []string{"a", "b"}},
+	}
+	for _, c := range cases {
+		got := tokenizeQuery(c.in)
+		if !reflect.DeepEqual(got, c.want) {
+			t.Errorf("tokenizeQuery(%q) = %#v, want %#v", c.in, got, c.want)
+		}
+	}
+}
+
+func TestParseQuery(t *testing.T) {
+	cases := []struct {
+		name    string
+		pattern string
+		wantOK  bool
+		want    AndNode
+	}{
+		{
+			name:    "single field",
+			pattern: "class:FFoo",
+			wantOK:  true,
+			want:    AndNode{Children: []interface{}{AtomNode{Field: "class", Value: "FFoo"}}},
+		},
+		{
+			name:    "negated field",
+			pattern: "-file:*_test.cpp",
+			wantOK:  true,
+			want:    AndNode{Children: []interface{}{NotNode{Child: AtomNode{Field: "file", Value: "*_test.cpp"}}}},
+		},
+		{
+			name:    "bare text plus field",
+			pattern: `func:Tick "hello world"`,
+			wantOK:  true,
+			want: AndNode{Children: []interface{}{
+				AtomNode{Field: "func", Value: "Tick"},
+				AtomNode{Field: "text", Value: "hello world"},
+			}},
+		},
+		{
+			name:    "empty quoted field value is malformed",
+			pattern: `class:""`,
+			wantOK:  false,
+		},
+		{
+			name:    "empty quoted bare term is malformed",
+			pattern: `""`,
+			wantOK:  false,
+		},
+		{
+			name:    "unbalanced quotes is malformed",
+			pattern: `sym:"Foo`,
+			wantOK:  false,
+		},
+		{
+			name:    "empty pattern is malformed",
+			pattern: "",
+			wantOK:  false,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, ok := parseQuery(c.pattern)
+			if ok != c.wantOK {
+				t.Fatalf("parseQuery(%q) ok = %v, want %v", c.pattern, ok, c.wantOK)
+			}
+			if ok && !reflect.DeepEqual(got, c.want) {
+				t.Errorf("parseQuery(%q) = %#v, want %#v", c.pattern, got, c.want)
+			}
+		})
+	}
+}
+
+func TestLooksLikeQuery(t *testing.T) {
+	cases := []struct {
+		pattern string
+		want    bool
+	}{
+		{"file:*.cpp", true},
+		{"class:FFoo", true},
+		{"plain text search", false},
+		{"file path without operator", false},
+	}
+	for _, c := range cases {
+		if got := looksLikeQuery(c.pattern); got != c.want {
+			t.Errorf("looksLikeQuery(%q) = %v, want %v", c.pattern, got, c.want)
+		}
+	}
+}
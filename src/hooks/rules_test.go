@@ -0,0 +1,103 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestCaptureValues(t *testing.T) {
+	re := regexp.MustCompile(`^class (\w+)$`)
+
+	cases := []struct {
+		name    string
+		names   []string
+		pattern string
+		want    map[string]string
+	}{
+		{
+			name:    "named group",
+			names:   []string{"name"},
+			pattern: "class FFoo",
+			want:    map[string]string{"name": "FFoo"},
+		},
+		{
+			name:    "no match",
+			names:   []string{"name"},
+			pattern: "struct FFoo",
+			want:    nil,
+		},
+		{
+			name:    "no capture names requested",
+			names:   nil,
+			pattern: "class FFoo",
+			want:    nil,
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := captureValues(re, c.names, c.pattern)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("captureValues(%q) = %#v, want %#v", c.pattern, got, c.want)
+			}
+		})
+	}
+}
+
+func TestCaptureValuesWholeMatchFallback(t *testing.T) {
+	re := regexp.MustCompile(`FTick\w*`)
+	got := captureValues(re, []string{"name"}, "void FTickFunction()")
+	want := map[string]string{"name": "FTickFunction"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("captureValues() = %#v, want %#v", got, want)
+	}
+}
+
+func TestExpandCaptures(t *testing.T) {
+	tmpl := "[unreal-index] {{.name}} found in {{.file}}"
+	captures := map[string]string{"name": "FFoo", "file": "FFoo.h"}
+	want := "[unreal-index] FFoo found in FFoo.h"
+	if got := expandCaptures(tmpl, captures); got != want {
+		t.Errorf("expandCaptures() = %q, want %q", got, want)
+	}
+}
+
+func TestLoadRulesMergesUserAndBuiltin(t *testing.T) {
+	dir := t.TempDir()
+	yamlRule := `
+name: log-macro
+tool: Grep
+pattern: 'UE_LOG\((\w+)'
+capture: ["name"]
+route: /grep
+query:
+  pattern: "{{.name}}"
+`
+	if err := os.WriteFile(filepath.Join(dir, "log-macro.yaml"), []byte(yamlRule), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("UNREAL_CLAUDE_RULES_DIR", dir)
+
+	rules := loadRules()
+	if len(rules) != 1+len(builtinRules) {
+		t.Fatalf("loadRules() returned %d rules, want %d", len(rules), 1+len(builtinRules))
+	}
+	if rules[0].Name != "log-macro" {
+		t.Errorf("loadRules()[0].Name = %q, want %q", rules[0].Name, "log-macro")
+	}
+}
+
+func TestLoadRulesSkipsMalformedFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "bad.yaml"), []byte("pattern: '(unbalanced'"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("UNREAL_CLAUDE_RULES_DIR", dir)
+
+	rules := loadRules()
+	if len(rules) != len(builtinRules) {
+		t.Fatalf("loadRules() returned %d rules, want %d (malformed file should be skipped)", len(rules), len(builtinRules))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEnvDuration(t *testing.T) {
+	const env = "UNREAL_CLAUDE_TEST_TIMEOUT"
+	fallback := 4 * time.Second
+
+	cases := []struct {
+		name  string
+		value string
+		want  time.Duration
+	}{
+		{"unset falls back", "", fallback},
+		{"valid seconds", "7", 7 * time.Second},
+		{"zero falls back", "0", fallback},
+		{"negative falls back", "-1", fallback},
+		{"unparseable falls back", "soon", fallback},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			t.Setenv(env, c.value)
+			if got := envDuration(env, fallback); got != c.want {
+				t.Errorf("envDuration(%q=%q) = %v, want %v", env, c.value, got, c.want)
+			}
+		})
+	}
+}
@@ -1,19 +1,53 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
-	"net/http"
 	"net/url"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 	"time"
+
+	"github.com/Joxx0r/UnrealClaudeFileHelper/internal/indexclient"
 )
 
 const serviceURL = "http://127.0.0.1:3847"
-const timeout = 5 * time.Second
+
+const (
+	defaultTimeoutTotal   = 4 * time.Second
+	defaultTimeoutPerCall = 2 * time.Second
+)
+
+var client = indexclient.New(serviceURL, envDuration("UNREAL_CLAUDE_TIMEOUT_PER_CALL", defaultTimeoutPerCall))
+
+// envDuration reads a whole-number-of-seconds override from env, or
+// returns fallback if unset or unparseable.
+func envDuration(env string, fallback time.Duration) time.Duration {
+	v := os.Getenv(env)
+	if v == "" {
+		return fallback
+	}
+	secs, err := strconv.Atoi(v)
+	if err != nil || secs <= 0 {
+		return fallback
+	}
+	return time.Duration(secs) * time.Second
+}
+
+type (
+	GrepResult         = indexclient.GrepResult
+	GrepResponse       = indexclient.GrepResponse
+	FindFileResult     = indexclient.FindFileResult
+	FindFileResponse   = indexclient.FindFileResponse
+	FindTypeResult     = indexclient.FindTypeResult
+	FindTypeResponse   = indexclient.FindTypeResponse
+	FindMemberResult   = indexclient.FindMemberResult
+	FindMemberResponse = indexclient.FindMemberResponse
+)
 
 // ── Regex patterns ───────────────────────────────────────────
 
@@ -49,75 +83,65 @@ type HookInput struct {
 
 type HookOutput struct {
 	HSO struct {
-		Event    string `json:"hookEventName"`
-		Decision string `json:"permissionDecision"`
-		Reason   string `json:"permissionDecisionReason"`
+		Event    string      `json:"hookEventName"`
+		Decision string      `json:"permissionDecision"`
+		Reason   string      `json:"permissionDecisionReason"`
+		Results  interface{} `json:"results,omitempty"`
 	} `json:"hookSpecificOutput"`
 }
 
-type GrepResult struct {
-	File    string   `json:"file"`
-	Line    int      `json:"line"`
-	Match   string   `json:"match"`
-	Context []string `json:"context"`
-}
-
-type GrepResponse struct {
-	Results      []GrepResult `json:"results"`
-	TotalMatches int          `json:"totalMatches"`
-	Truncated    bool         `json:"truncated"`
-	Error        string       `json:"error"`
-}
-
-type FindFileResult struct {
-	File string `json:"file"`
-}
-
-type FindFileResponse struct {
-	Results []FindFileResult `json:"results"`
-	Error   string           `json:"error"`
-}
-
-type FindTypeResult struct {
-	Name    string `json:"name"`
-	Kind    string `json:"kind"`
-	Project string `json:"project"`
-	Path    string `json:"path"`
-	Line    int    `json:"line"`
-}
-
-type FindTypeResponse struct {
-	Results []FindTypeResult `json:"results"`
-	Error   string           `json:"error"`
-}
-
-type FindMemberResult struct {
-	Name      string `json:"name"`
-	OwnerName string `json:"ownerName"`
-	Kind      string `json:"memberKind"`
-	Path      string `json:"path"`
-	Line      int    `json:"line"`
-}
-
-type FindMemberResponse struct {
-	Results []FindMemberResult `json:"results"`
-	Error   string             `json:"error"`
-}
-
 // ── Helpers ──────────────────────────────────────────────────
 
 func allow() { os.Exit(0) }
 
-func deny(reason string) {
+// outputFormat reads UNREAL_CLAUDE_FORMAT (json|text|both) to decide
+// what denyStructured emits. Defaults to "text" to match the
+// pre-existing plain-Reason behavior.
+func outputFormat() string {
+	switch os.Getenv("UNREAL_CLAUDE_FORMAT") {
+	case "json":
+		return "json"
+	case "both":
+		return "both"
+	default:
+		return "text"
+	}
+}
+
+// buildHookOutput assembles the HookOutput for a denial according to
+// format (json|text|both), split out from denyStructured so the
+// decision logic can be unit-tested without the process exiting.
+func buildHookOutput(event string, results interface{}, humanText, format string) HookOutput {
 	out := HookOutput{}
-	out.HSO.Event = "PreToolUse"
+	out.HSO.Event = event
 	out.HSO.Decision = "deny"
-	out.HSO.Reason = reason
+	switch format {
+	case "json":
+		out.HSO.Results = results
+	case "both":
+		out.HSO.Reason = humanText
+		out.HSO.Results = results
+	default:
+		out.HSO.Reason = humanText
+	}
+	return out
+}
+
+// denyStructured is the single exit point for every denial. results
+// carries the raw GrepResult/FindFileResult/FindTypeResult/
+// FindMemberResult slice behind the decision, for callers that want
+// to parse the hook output instead of scraping humanText.
+func denyStructured(event string, results interface{}, humanText string) {
+	out := buildHookOutput(event, results, humanText, outputFormat())
 	data, _ := json.Marshal(out)
 	os.Stdout.Write(data)
 	os.Exit(0)
 }
 
+func deny(reason string) {
+	denyStructured("PreToolUse", nil, reason)
+}
+
 func str(m map[string]interface{}, k string) string {
 	if v, ok := m[k]; ok {
 		if s, ok := v.(string); ok {
@@ -161,33 +185,12 @@ func inferLang(glob, typ string) string {
 	return ""
 }
 
-func fetchJSON(u string, target interface{}) bool {
-	client := &http.Client{Timeout: timeout}
-	resp, err := client.Get(u)
-	if err != nil || resp.StatusCode != 200 {
-		if resp != nil {
-			resp.Body.Close()
-		}
-		return false
-	}
-	defer resp.Body.Close()
-	body, err := io.ReadAll(resp.Body)
-	if err != nil {
-		return false
-	}
-	return json.Unmarshal(body, target) == nil
-}
-
 // ── Smart routing: try find-type ─────────────────────────────
 
-func tryFindType(name string) string {
-	p := url.Values{}
-	p.Set("name", name)
-	p.Set("maxResults", "20")
-
-	var data FindTypeResponse
-	if !fetchJSON(serviceURL+"/find-type?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
-		return ""
+func tryFindType(ctx context.Context, name string) (string, []FindTypeResult) {
+	data, ok := client.FindType(ctx, name, 20)
+	if !ok || len(data.Results) == 0 {
+		return "", nil
 	}
 
 	var lines []string
@@ -197,19 +200,15 @@ func tryFindType(name string) string {
 	return fmt.Sprintf(
 		"[unreal-index] Smart-routed to /find-type for \"%s\":\n\n%s\n\n"+
 			"Precise type definition results from index.",
-		name, strings.Join(lines, "\n"))
+		name, strings.Join(lines, "\n")), data.Results
 }
 
 // ── Smart routing: try find-member ───────────────────────────
 
-func tryFindMember(name string) string {
-	p := url.Values{}
-	p.Set("name", name)
-	p.Set("maxResults", "20")
-
-	var data FindMemberResponse
-	if !fetchJSON(serviceURL+"/find-member?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
-		return ""
+func tryFindMember(ctx context.Context, name string) (string, []FindMemberResult) {
+	data, ok := client.FindMember(ctx, name, 20)
+	if !ok || len(data.Results) == 0 {
+		return "", nil
 	}
 
 	var lines []string
@@ -223,12 +222,12 @@ func tryFindMember(name string) string {
 	return fmt.Sprintf(
 		"[unreal-index] Smart-routed to /find-member for \"%s\":\n\n%s\n\n"+
 			"Precise member definition results from index.",
-		name, strings.Join(lines, "\n"))
+		name, strings.Join(lines, "\n")), data.Results
 }
 
 // ── Grep handler (with smart routing) ────────────────────────
 
-func handleGrep(ti map[string]interface{}) {
+func handleGrep(ctx context.Context, rules []compiledRule, ti map[string]interface{}) {
 	pattern := str(ti, "pattern")
 	path := str(ti, "path")
 	outputMode := str(ti, "output_mode")
@@ -239,26 +238,15 @@ func handleGrep(ti map[string]interface{}) {
 		allow()
 	}
 
-	// Smart routing: detect type definition patterns
-	if m := classDefRe.FindStringSubmatch(pattern); m != nil {
-		if result := tryFindType(m[1]); result != "" {
-			deny(result)
-		}
-	}
-
-	// Smart routing: detect UE-prefixed type names (UAimComponent, FVector, etc.)
-	if uePrefixRe.MatchString(pattern) {
-		if result := tryFindType(pattern); result != "" {
-			deny(result)
-		}
-	}
+	// Query DSL: file:/lang:/sym:/class:/func:/-file:/case: operators
+	// take priority over the regex-based rules below. Always
+	// terminates the process once it recognizes a query.
+	handleQueryDSL(ctx, pattern)
 
-	// Smart routing: detect function definition patterns
-	if m := funcDefRe.FindStringSubmatch(pattern); m != nil {
-		if result := tryFindMember(m[1]); result != "" {
-			deny(result)
-		}
-	}
+	// Smart routing: user rules, then the builtin ruleset, in order.
+	// A match with results denies (via denyStructured) from inside
+	// evaluateRules and never returns here.
+	evaluateRules(ctx, rules, "Grep", pattern)
 
 	// Fall through to regular grep
 	maxRes := int(num(ti, "head_limit"))
@@ -274,19 +262,19 @@ func handleGrep(ti map[string]interface{}) {
 	if flagVal(ti, "-i") {
 		p.Set("caseSensitive", "false")
 	}
-	ctx := num(ti, "-C")
-	if ctx == 0 {
-		ctx = num(ti, "context")
+	ctxLines := num(ti, "-C")
+	if ctxLines == 0 {
+		ctxLines = num(ti, "context")
 	}
-	if ctx > 0 {
-		p.Set("contextLines", fmt.Sprintf("%d", int(ctx)))
+	if ctxLines > 0 {
+		p.Set("contextLines", fmt.Sprintf("%d", int(ctxLines)))
 	}
 	if lang := inferLang(glob, typ); lang != "" {
 		p.Set("language", lang)
 	}
 
-	var data GrepResponse
-	if !fetchJSON(serviceURL+"/grep?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
+	data, ok := client.Grep(ctx, p)
+	if !ok || len(data.Results) == 0 {
 		allow()
 	}
 
@@ -338,7 +326,7 @@ func handleGrep(ti map[string]interface{}) {
 		trunc = fmt.Sprintf(" (%d of %d)", len(data.Results), data.TotalMatches)
 	}
 
-	deny(fmt.Sprintf(
+	denyStructured("PreToolUse", data.Results, fmt.Sprintf(
 		"[unreal-index] Grep intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
 			"Results from pre-built index. To search a specific file use Read. "+
 			"To search outside the indexed project, ask the user to allow direct Grep.",
@@ -347,9 +335,14 @@ func handleGrep(ti map[string]interface{}) {
 
 // ── Glob handler ─────────────────────────────────────────────
 
-func handleGlob(ti map[string]interface{}) {
+func handleGlob(ctx context.Context, rules []compiledRule, ti map[string]interface{}) {
 	pattern := str(ti, "pattern")
 
+	// Smart routing: user rules, then the builtin ruleset, in order.
+	// A match with results denies (via denyStructured) from inside
+	// evaluateRules and never returns here.
+	evaluateRules(ctx, rules, "Glob", pattern)
+
 	basename := pattern
 	if idx := strings.LastIndexAny(basename, "/\\"); idx >= 0 {
 		basename = basename[idx+1:]
@@ -362,12 +355,8 @@ func handleGlob(ti map[string]interface{}) {
 		allow()
 	}
 
-	p := url.Values{}
-	p.Set("filename", cleaned)
-	p.Set("maxResults", "30")
-
-	var data FindFileResponse
-	if !fetchJSON(serviceURL+"/find-file?"+p.Encode(), &data) || data.Error != "" || len(data.Results) == 0 {
+	data, ok := client.FindFile(ctx, cleaned, 30)
+	if !ok || len(data.Results) == 0 {
 		allow()
 	}
 
@@ -376,7 +365,7 @@ func handleGlob(ti map[string]interface{}) {
 		files = append(files, r.File)
 	}
 
-	deny(fmt.Sprintf(
+	denyStructured("PreToolUse", data.Results, fmt.Sprintf(
 		"[unreal-index] Glob intercepted — indexed results for \"%s\":\n\n%s\n\n"+
 			"Results from pre-built index. "+
 			"To search outside the indexed project, ask the user to allow direct Glob.",
@@ -385,7 +374,7 @@ func handleGlob(ti map[string]interface{}) {
 
 // ── Bash handler ─────────────────────────────────────────────
 
-func handleBash(ti map[string]interface{}) {
+func handleBash(ctx context.Context, rules []compiledRule, ti map[string]interface{}) {
 	cmd := str(ti, "command")
 	if cmd == "" {
 		allow()
@@ -394,6 +383,11 @@ func handleBash(ti map[string]interface{}) {
 	// Trim leading whitespace for matching
 	trimmed := strings.TrimSpace(cmd)
 
+	// Smart routing: user rules, then the builtin ruleset, in order.
+	// A match with results denies (via denyStructured) from inside
+	// evaluateRules and never returns here.
+	evaluateRules(ctx, rules, "Bash", trimmed)
+
 	// A. Directory listing: ls, dir, tree → block, redirect to Glob
 	if lsRe.MatchString(trimmed) {
 		deny(
@@ -412,17 +406,12 @@ func handleBash(ti map[string]interface{}) {
 				name = name[:idx]
 			}
 			if len(name) >= 3 {
-				p := url.Values{}
-				p.Set("filename", name)
-				p.Set("maxResults", "30")
-
-				var data FindFileResponse
-				if fetchJSON(serviceURL+"/find-file?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
+				if data, ok := client.FindFile(ctx, name, 30); ok && len(data.Results) > 0 {
 					var files []string
 					for _, r := range data.Results {
 						files = append(files, r.File)
 					}
-					deny(fmt.Sprintf(
+					denyStructured("PreToolUse", data.Results, fmt.Sprintf(
 						"[unreal-index] find command intercepted — indexed results for \"%s\":\n\n%s\n\n"+
 							"Results from pre-built index. Use Glob for file searches.",
 						name, strings.Join(files, "\n")))
@@ -447,8 +436,7 @@ func handleBash(ti map[string]interface{}) {
 			p.Set("grouped", "false")
 			p.Set("symbols", "false")
 
-			var data GrepResponse
-			if fetchJSON(serviceURL+"/grep?"+p.Encode(), &data) && data.Error == "" && len(data.Results) > 0 {
+			if data, ok := client.Grep(ctx, p); ok && len(data.Results) > 0 {
 				var lines []string
 				for _, r := range data.Results {
 					lines = append(lines, fmt.Sprintf("%s:%d: %s", r.File, r.Line, r.Match))
@@ -457,7 +445,7 @@ func handleBash(ti map[string]interface{}) {
 				if data.Truncated {
 					trunc = fmt.Sprintf(" (%d of %d)", len(data.Results), data.TotalMatches)
 				}
-				deny(fmt.Sprintf(
+				denyStructured("PreToolUse", data.Results, fmt.Sprintf(
 					"[unreal-index] grep/rg intercepted — indexed results for \"%s\"%s:\n\n%s\n\n"+
 						"Results from pre-built index. Use the Grep tool instead of shell grep.",
 					pattern, trunc, strings.Join(lines, "\n")))
@@ -494,13 +482,23 @@ func main() {
 		allow()
 	}
 
+	rules := loadRules()
+
+	// A single hook invocation may fire several sequential index
+	// requests (tryFindType, tryFindMember, /grep); this total budget
+	// is shared across all of them via ctx, so a slow first call
+	// leaves less time for the rest instead of each paying its own
+	// full per-call timeout.
+	ctx, cancel := context.WithTimeout(context.Background(), envDuration("UNREAL_CLAUDE_TIMEOUT_TOTAL", defaultTimeoutTotal))
+	defer cancel()
+
 	switch input.ToolName {
 	case "Grep":
-		handleGrep(input.ToolInput)
+		handleGrep(ctx, rules, input.ToolInput)
 	case "Glob":
-		handleGlob(input.ToolInput)
+		handleGlob(ctx, rules, input.ToolInput)
 	case "Bash":
-		handleBash(input.ToolInput)
+		handleBash(ctx, rules, input.ToolInput)
 	default:
 		allow()
 	}
@@ -0,0 +1,58 @@
+package main
+
+import "testing"
+
+func TestOutputFormat(t *testing.T) {
+	cases := []struct {
+		env  string
+		want string
+	}{
+		{"", "text"},
+		{"text", "text"},
+		{"json", "json"},
+		{"both", "both"},
+		{"garbage", "text"},
+	}
+	for _, c := range cases {
+		t.Setenv("UNREAL_CLAUDE_FORMAT", c.env)
+		if got := outputFormat(); got != c.want {
+			t.Errorf("outputFormat() with UNREAL_CLAUDE_FORMAT=%q = %q, want %q", c.env, got, c.want)
+		}
+	}
+}
+
+func TestBuildHookOutputText(t *testing.T) {
+	out := buildHookOutput("PreToolUse", []string{"ignored"}, "human readable reason", "text")
+	if out.HSO.Decision != "deny" {
+		t.Errorf("Decision = %q, want %q", out.HSO.Decision, "deny")
+	}
+	if out.HSO.Reason != "human readable reason" {
+		t.Errorf("Reason = %q, want the human text", out.HSO.Reason)
+	}
+	if out.HSO.Results != nil {
+		t.Errorf("Results = %#v, want nil in text mode", out.HSO.Results)
+	}
+}
+
+func TestBuildHookOutputJSON(t *testing.T) {
+	results := []string{"a", "b"}
+	out := buildHookOutput("PreToolUse", results, "human readable reason", "json")
+	if out.HSO.Reason != "" {
+		t.Errorf("Reason = %q, want empty in json mode", out.HSO.Reason)
+	}
+	got, ok := out.HSO.Results.([]string)
+	if !ok || len(got) != 2 {
+		t.Errorf("Results = %#v, want %#v", out.HSO.Results, results)
+	}
+}
+
+func TestBuildHookOutputBoth(t *testing.T) {
+	results := []string{"a"}
+	out := buildHookOutput("PreToolUse", results, "human readable reason", "both")
+	if out.HSO.Reason != "human readable reason" {
+		t.Errorf("Reason = %q, want the human text in both mode", out.HSO.Reason)
+	}
+	if out.HSO.Results == nil {
+		t.Error("Results = nil, want the results slice in both mode")
+	}
+}
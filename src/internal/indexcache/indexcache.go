@@ -0,0 +1,274 @@
+// Package indexcache is a small on-disk cache for unreal-index query
+// results, so the repeated Grep/Glob patterns a single Claude Code
+// session tends to re-fire don't each round-trip to the index service.
+// Entries are keyed by a hash of (endpoint, sorted query params) and
+// persisted as a flat JSON file that is rewritten (with fsync) on every
+// write — good enough for the handful of entries one session generates,
+// without pulling in an embedded-database dependency this repo doesn't
+// otherwise have.
+package indexcache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultTTL = 60 * time.Second
+
+// Entry is one cached response, stored alongside the bits needed to
+// decide whether it's still usable: when it was stored, the build id
+// of the index service that produced it, and (for /grep results) the
+// pattern, non-pattern filters and truncation state used for prefix
+// short-circuiting.
+type Entry struct {
+	Endpoint  string          `json:"endpoint"`
+	Body      json.RawMessage `json:"body"`
+	Pattern   string          `json:"pattern,omitempty"`
+	Filters   string          `json:"filters,omitempty"`
+	Truncated bool            `json:"truncated"`
+	StoredAt  time.Time       `json:"storedAt"`
+	BuildID   string          `json:"buildId"`
+}
+
+// file is the on-disk shape: entries plus the build id they were
+// captured under, so the first /version probe after a restart can tell
+// a stale cache from a fresh one.
+type file struct {
+	BuildID string           `json:"buildId"`
+	Entries map[string]Entry `json:"entries"`
+}
+
+// Cache is safe for concurrent use.
+type Cache struct {
+	mu      sync.Mutex
+	path    string
+	ttl     time.Duration
+	buildID string
+	entries map[string]Entry
+}
+
+// New loads (or creates) the on-disk cache at the configured path. ttl
+// of zero or less disables both reads and writes, so callers can wire
+// this up unconditionally and let the env var or a test turn it off.
+func New(ttl time.Duration) *Cache {
+	c := &Cache{path: cachePath(), ttl: ttl, entries: map[string]Entry{}}
+	c.load()
+	return c
+}
+
+func cachePath() string {
+	if d := os.Getenv("UNREAL_CLAUDE_CACHE_DIR"); d != "" {
+		return filepath.Join(d, "cache.json")
+	}
+	if xdg := os.Getenv("XDG_CACHE_HOME"); xdg != "" {
+		return filepath.Join(xdg, "unreal-claude", "cache.json")
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".cache", "unreal-claude", "cache.json")
+}
+
+// TTLFromEnv reads UNREAL_CLAUDE_CACHE_TTL (a time.ParseDuration
+// string, e.g. "30s") or falls back to defaultTTL.
+func TTLFromEnv() time.Duration {
+	if s := os.Getenv("UNREAL_CLAUDE_CACHE_TTL"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil {
+			return d
+		}
+	}
+	return defaultTTL
+}
+
+func (c *Cache) load() {
+	if c.path == "" {
+		return
+	}
+	data, err := os.ReadFile(c.path)
+	if err != nil {
+		return
+	}
+	var f file
+	if json.Unmarshal(data, &f) != nil {
+		return
+	}
+	c.buildID = f.BuildID
+	if f.Entries != nil {
+		c.entries = f.Entries
+	}
+}
+
+// persist rewrites the whole cache file and fsyncs it, under c.mu.
+func (c *Cache) persist() {
+	if c.path == "" {
+		return
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return
+	}
+	data, err := json.Marshal(file{BuildID: c.buildID, Entries: c.entries})
+	if err != nil {
+		return
+	}
+	tmp := c.path + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return
+	}
+	if err := f.Close(); err != nil {
+		return
+	}
+	os.Rename(tmp, c.path)
+}
+
+// Empty reports whether the cache is disabled or currently holds no
+// entries — callers can use this to skip work (like a /version probe)
+// that exists only to invalidate entries that aren't there yet.
+func (c *Cache) Empty() bool {
+	if c.ttl <= 0 {
+		return true
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return len(c.entries) == 0
+}
+
+// key hashes (endpoint, sorted params) into a stable map key.
+func key(endpoint string, params url.Values) string {
+	var parts []string
+	for k, vs := range params {
+		for _, v := range vs {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	sort.Strings(parts)
+	h := sha256.Sum256([]byte(endpoint + "?" + strings.Join(parts, "&")))
+	return hex.EncodeToString(h[:])
+}
+
+// prefixFilterParams are the /grep query params (beyond pattern itself)
+// that change which matches come back. A prefix-compatible entry is
+// only a safe superset when every one of these was identical between
+// the cached query and the new one.
+var prefixFilterParams = []string{"language", "pathGlob", "excludeGlob", "caseSensitive"}
+
+// filterKey canonicalizes the prefixFilterParams subset of params into
+// a stable string, so two queries that differ only in pattern (and
+// agree on language/path/case filters) produce the same key.
+func filterKey(params url.Values) string {
+	var parts []string
+	for _, k := range prefixFilterParams {
+		if v := params.Get(k); v != "" {
+			parts = append(parts, k+"="+v)
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// Get returns the cached body for (endpoint, params) if present and
+// younger than the TTL.
+func (c *Cache) Get(endpoint string, params url.Values) (json.RawMessage, bool) {
+	if c.ttl <= 0 {
+		return nil, false
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key(endpoint, params)]
+	if !ok || time.Since(e.StoredAt) > c.ttl {
+		return nil, false
+	}
+	return e.Body, true
+}
+
+// Put stores body under (endpoint, params). pattern and truncated are
+// only meaningful for /grep responses; callers that don't have them
+// pass "" and false, which simply makes the entry ineligible for
+// prefix short-circuiting.
+func (c *Cache) Put(endpoint string, params url.Values, body json.RawMessage, pattern string, truncated bool) {
+	if c.ttl <= 0 {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.entries[key(endpoint, params)] = Entry{
+		Endpoint:  endpoint,
+		Body:      body,
+		Pattern:   pattern,
+		Filters:   filterKey(params),
+		Truncated: truncated,
+		StoredAt:  time.Now(),
+		BuildID:   c.buildID,
+	}
+	c.persist()
+}
+
+// GetPrefixCompatible looks for a cached, non-truncated /grep entry
+// whose pattern is a (strict) prefix of params' pattern and whose
+// language/pathGlob/excludeGlob/caseSensitive filters are identical —
+// only then is its result set guaranteed to be a superset of what the
+// (longer, more specific) query would return — and the caller can
+// filter it locally instead of issuing a new request.
+func (c *Cache) GetPrefixCompatible(endpoint string, params url.Values) (Entry, bool) {
+	pattern := params.Get("pattern")
+	if c.ttl <= 0 || pattern == "" {
+		return Entry{}, false
+	}
+	filters := filterKey(params)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, e := range c.entries {
+		if e.Endpoint != endpoint || e.Truncated || e.Pattern == "" {
+			continue
+		}
+		if e.Filters != filters {
+			continue
+		}
+		if time.Since(e.StoredAt) > c.ttl {
+			continue
+		}
+		if e.Pattern == pattern || !strings.HasPrefix(pattern, e.Pattern) {
+			continue
+		}
+		return e, true
+	}
+	return Entry{}, false
+}
+
+// SetBuildID compares id against the build id the cache was last
+// persisted under. A mismatch means the index was rebuilt since, so
+// every cached entry is now potentially stale and is dropped.
+func (c *Cache) SetBuildID(id string) {
+	if id == "" {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.buildID == id {
+		return
+	}
+	c.buildID = id
+	c.entries = map[string]Entry{}
+	c.persist()
+}
@@ -0,0 +1,97 @@
+package indexcache
+
+import (
+	"encoding/json"
+	"net/url"
+	"testing"
+	"time"
+)
+
+func newTestCache(t *testing.T) *Cache {
+	t.Helper()
+	return &Cache{path: "", ttl: time.Minute, entries: map[string]Entry{}}
+}
+
+func TestGetPrefixCompatibleMatch(t *testing.T) {
+	c := newTestCache(t)
+	params := url.Values{}
+	params.Set("pattern", "Tick")
+	params.Set("language", "cpp")
+	c.Put("/grep", params, json.RawMessage(`{"results":[{"match":"Tick"},{"match":"TickFunction"}]}`), "Tick", false)
+
+	query := url.Values{}
+	query.Set("pattern", "TickFunction")
+	query.Set("language", "cpp")
+	if _, ok := c.GetPrefixCompatible("/grep", query); !ok {
+		t.Fatal("expected a prefix-compatible hit when filters match")
+	}
+}
+
+func TestGetPrefixCompatibleRejectsLanguageMismatch(t *testing.T) {
+	c := newTestCache(t)
+	params := url.Values{}
+	params.Set("pattern", "Tick")
+	params.Set("language", "cpp")
+	c.Put("/grep", params, json.RawMessage(`{"results":[]}`), "Tick", false)
+
+	query := url.Values{}
+	query.Set("pattern", "TickFunction")
+	query.Set("language", "go")
+	if _, ok := c.GetPrefixCompatible("/grep", query); ok {
+		t.Fatal("expected no hit when language filter differs from the cached entry")
+	}
+}
+
+func TestGetPrefixCompatibleRejectsCaseSensitivityMismatch(t *testing.T) {
+	c := newTestCache(t)
+	params := url.Values{}
+	params.Set("pattern", "Tick")
+	params.Set("caseSensitive", "false")
+	c.Put("/grep", params, json.RawMessage(`{"results":[]}`), "Tick", false)
+
+	query := url.Values{}
+	query.Set("pattern", "TickFunction")
+	query.Set("caseSensitive", "true")
+	if _, ok := c.GetPrefixCompatible("/grep", query); ok {
+		t.Fatal("expected no hit when caseSensitive filter differs from the cached entry")
+	}
+}
+
+func TestGetPrefixCompatibleRejectsNonPrefix(t *testing.T) {
+	c := newTestCache(t)
+	params := url.Values{}
+	params.Set("pattern", "Tick")
+	c.Put("/grep", params, json.RawMessage(`{"results":[]}`), "Tick", false)
+
+	query := url.Values{}
+	query.Set("pattern", "Other")
+	if _, ok := c.GetPrefixCompatible("/grep", query); ok {
+		t.Fatal("expected no hit when the cached pattern isn't a prefix of the query")
+	}
+}
+
+func TestGetPrefixCompatibleRejectsTruncated(t *testing.T) {
+	c := newTestCache(t)
+	params := url.Values{}
+	params.Set("pattern", "Tick")
+	c.Put("/grep", params, json.RawMessage(`{"results":[],"truncated":true}`), "Tick", true)
+
+	query := url.Values{}
+	query.Set("pattern", "TickFunction")
+	if _, ok := c.GetPrefixCompatible("/grep", query); ok {
+		t.Fatal("expected no hit when the cached entry was truncated")
+	}
+}
+
+func TestGetPrefixCompatibleMatchesShortCachedPattern(t *testing.T) {
+	c := newTestCache(t)
+	params := url.Values{}
+	params.Set("pattern", "Ti")
+	c.Put("/grep", params, json.RawMessage(`{"results":[{"match":"Tick"},{"match":"TickFunction"}]}`), "Ti", false)
+
+	query := url.Values{}
+	query.Set("pattern", "TickFunction")
+	if _, ok := c.GetPrefixCompatible("/grep", query); !ok {
+		t.Fatal("expected a 2-char cached pattern to still short-circuit a longer query")
+	}
+}
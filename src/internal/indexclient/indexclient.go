@@ -0,0 +1,309 @@
+// Package indexclient is the shared JSON plumbing for talking to the
+// unreal-index service (http://127.0.0.1:3847 by default). It backs
+// both the Claude Code hook (src/hooks) and the `unrealclaudefile lsp`
+// subcommand, so the two stay on one set of result types and one
+// fetch path instead of drifting apart.
+package indexclient
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Joxx0r/UnrealClaudeFileHelper/internal/indexcache"
+)
+
+// sharedTransport is reused across every Client so that Grep smart-
+// routing's 2-3 sequential requests per hook invocation (tryFindType,
+// tryFindMember, /grep) reuse a pooled connection instead of paying a
+// fresh TCP+handshake cost each time.
+var sharedTransport = &http.Transport{
+	MaxIdleConnsPerHost: 10,
+	IdleConnTimeout:     90 * time.Second,
+}
+
+var sharedHTTPClient = &http.Client{Transport: sharedTransport}
+
+// Client talks to one index service. PerCallTimeout bounds each
+// individual request; callers additionally pass a parent
+// context.Context (typically carrying a total budget for the whole
+// hook invocation) so a chain of sequential calls can't each spend
+// the full per-call timeout once the shared budget is nearly gone.
+type Client struct {
+	BaseURL        string
+	PerCallTimeout time.Duration
+	http           *http.Client
+	cache          *indexcache.Cache
+	versionOnce    sync.Once
+}
+
+func New(baseURL string, perCallTimeout time.Duration) *Client {
+	return &Client{
+		BaseURL:        baseURL,
+		PerCallTimeout: perCallTimeout,
+		http:           sharedHTTPClient,
+		cache:          indexcache.New(indexcache.TTLFromEnv()),
+	}
+}
+
+// FetchJSON is the generic GET-and-decode call, exported for routes
+// that don't have a typed method below (e.g. a user-defined rule's
+// arbitrary route). It derives a per-call deadline from ctx, so a
+// caller that passes a context already near its own deadline gets cut
+// off early rather than waiting the full PerCallTimeout.
+//
+// Responses are served from the on-disk cache when a fresh-enough
+// entry exists for (path, params), and every live fetch is written
+// back to the cache for later hits.
+func (c *Client) FetchJSON(ctx context.Context, path string, params url.Values, target interface{}) bool {
+	c.ensureVersionChecked(ctx)
+
+	if body, ok := c.cache.Get(path, params); ok {
+		return json.Unmarshal(body, target) == nil
+	}
+
+	body, ok := c.rawGet(ctx, path, params)
+	if !ok {
+		return false
+	}
+	if json.Unmarshal(body, target) != nil {
+		return false
+	}
+
+	truncated := false
+	if g, ok := target.(*GrepResponse); ok {
+		truncated = g.Truncated
+	}
+	c.cache.Put(path, params, body, params.Get("pattern"), truncated)
+	return true
+}
+
+// rawGet performs the HTTP round-trip and returns the raw response
+// body, bypassing the cache entirely — used by FetchJSON for a miss
+// and by the /version probe, which should never itself be cached.
+func (c *Client) rawGet(ctx context.Context, path string, params url.Values) ([]byte, bool) {
+	ctx, cancel := context.WithTimeout(ctx, c.PerCallTimeout)
+	defer cancel()
+
+	u := c.BaseURL + path
+	if params != nil {
+		u += "?" + params.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, false
+	}
+	resp, err := c.http.Do(req)
+	if err != nil || resp.StatusCode != 200 {
+		if resp != nil {
+			resp.Body.Close()
+		}
+		return nil, false
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false
+	}
+	return body, true
+}
+
+// versionProbeTimeout bounds the /version probe on its own, separate
+// from PerCallTimeout. The probe only exists to invalidate stale
+// entries, so it must never eat more than a sliver of the shared
+// per-invocation budget that the real tryFindType/tryFindMember/grep
+// chain depends on.
+const versionProbeTimeout = 500 * time.Millisecond
+
+// ensureVersionChecked issues the /version probe at most once per
+// process and, if the reported build id differs from the one the
+// on-disk cache was last persisted under, drops every cached entry —
+// the index was rebuilt since, so none of them can be trusted. Skipped
+// entirely when the cache is disabled or has no entries yet, since
+// there's nothing for a stale build id to invalidate. When it does run,
+// it's capped at versionProbeTimeout rather than the full per-call
+// budget, so it can't starve the calls that follow it in the same
+// ctx-bounded chain.
+func (c *Client) ensureVersionChecked(ctx context.Context) {
+	if c.cache.Empty() {
+		return
+	}
+	c.versionOnce.Do(func() {
+		probeCtx, cancel := context.WithTimeout(ctx, versionProbeTimeout)
+		defer cancel()
+
+		var v struct {
+			BuildID string `json:"buildId"`
+		}
+		body, ok := c.rawGet(probeCtx, "/version", nil)
+		if !ok || json.Unmarshal(body, &v) != nil {
+			return
+		}
+		c.cache.SetBuildID(v.BuildID)
+	})
+}
+
+type GrepResult struct {
+	File    string   `json:"file"`
+	Line    int      `json:"line"`
+	Match   string   `json:"match"`
+	Context []string `json:"context"`
+}
+
+type GrepResponse struct {
+	Results      []GrepResult `json:"results"`
+	TotalMatches int          `json:"totalMatches"`
+	Truncated    bool         `json:"truncated"`
+	Error        string       `json:"error"`
+}
+
+type FindFileResult struct {
+	File string `json:"file"`
+}
+
+type FindFileResponse struct {
+	Results []FindFileResult `json:"results"`
+	Error   string           `json:"error"`
+}
+
+type FindTypeResult struct {
+	Name    string `json:"name"`
+	Kind    string `json:"kind"`
+	Project string `json:"project"`
+	Path    string `json:"path"`
+	Line    int    `json:"line"`
+}
+
+type FindTypeResponse struct {
+	Results []FindTypeResult `json:"results"`
+	Error   string           `json:"error"`
+}
+
+type FindMemberResult struct {
+	Name      string `json:"name"`
+	OwnerName string `json:"ownerName"`
+	Kind      string `json:"memberKind"`
+	Path      string `json:"path"`
+	Line      int    `json:"line"`
+}
+
+type FindMemberResponse struct {
+	Results []FindMemberResult `json:"results"`
+	Error   string             `json:"error"`
+}
+
+// FileSymbolResult is a single symbol returned by /file-symbols.
+type FileSymbolResult struct {
+	Name string `json:"name"`
+	Kind string `json:"kind"`
+	Line int    `json:"line"`
+}
+
+type FileSymbolsResponse struct {
+	Results []FileSymbolResult `json:"results"`
+	Error   string             `json:"error"`
+}
+
+func (c *Client) FindType(ctx context.Context, name string, maxResults int) (*FindTypeResponse, bool) {
+	p := url.Values{}
+	p.Set("name", name)
+	p.Set("maxResults", strconv.Itoa(maxResults))
+
+	var data FindTypeResponse
+	if !c.FetchJSON(ctx, "/find-type", p, &data) || data.Error != "" {
+		return nil, false
+	}
+	return &data, true
+}
+
+func (c *Client) FindMember(ctx context.Context, name string, maxResults int) (*FindMemberResponse, bool) {
+	p := url.Values{}
+	p.Set("name", name)
+	p.Set("maxResults", strconv.Itoa(maxResults))
+
+	var data FindMemberResponse
+	if !c.FetchJSON(ctx, "/find-member", p, &data) || data.Error != "" {
+		return nil, false
+	}
+	return &data, true
+}
+
+// Grep first looks for a cached, non-truncated result for a prefix of
+// this pattern — its matches are a superset of what this (longer,
+// more specific) pattern would return, so they can be filtered locally
+// instead of issuing a new request. Falls through to FetchJSON (and
+// its own exact-match cache) on a miss.
+func (c *Client) Grep(ctx context.Context, params url.Values) (*GrepResponse, bool) {
+	if pattern := params.Get("pattern"); pattern != "" {
+		if e, ok := c.cache.GetPrefixCompatible("/grep", params); ok {
+			var superset GrepResponse
+			if json.Unmarshal(e.Body, &superset) == nil {
+				caseInsensitive := params.Get("caseSensitive") == "false"
+				return filterGrepResponse(superset, pattern, caseInsensitive), true
+			}
+		}
+	}
+
+	var data GrepResponse
+	if !c.FetchJSON(ctx, "/grep", params, &data) || data.Error != "" {
+		return nil, false
+	}
+	return &data, true
+}
+
+// filterGrepResponse narrows a cached superset response down to the
+// matches that also satisfy the fuller pattern. caseInsensitive mirrors
+// the caseSensitive=false query param, so a follow-up query run with
+// -i still matches case-insensitively against the cached superset
+// instead of silently dropping results the real /grep call would have
+// returned.
+func filterGrepResponse(superset GrepResponse, pattern string, caseInsensitive bool) *GrepResponse {
+	if caseInsensitive {
+		pattern = strings.ToLower(pattern)
+	}
+	filtered := GrepResponse{Truncated: false}
+	for _, r := range superset.Results {
+		match := r.Match
+		if caseInsensitive {
+			match = strings.ToLower(match)
+		}
+		if strings.Contains(match, pattern) {
+			filtered.Results = append(filtered.Results, r)
+		}
+	}
+	filtered.TotalMatches = len(filtered.Results)
+	return &filtered
+}
+
+func (c *Client) FindFile(ctx context.Context, filename string, maxResults int) (*FindFileResponse, bool) {
+	p := url.Values{}
+	p.Set("filename", filename)
+	p.Set("maxResults", strconv.Itoa(maxResults))
+
+	var data FindFileResponse
+	if !c.FetchJSON(ctx, "/find-file", p, &data) || data.Error != "" {
+		return nil, false
+	}
+	return &data, true
+}
+
+// FileSymbols calls /file-symbols?path=..., a new endpoint this
+// client expects for textDocument/documentSymbol support.
+// TODO: the index service does not expose /file-symbols yet — wire
+// this up for real once it does.
+func (c *Client) FileSymbols(ctx context.Context, path string) (*FileSymbolsResponse, bool) {
+	p := url.Values{}
+	p.Set("path", path)
+
+	var data FileSymbolsResponse
+	if !c.FetchJSON(ctx, "/file-symbols", p, &data) || data.Error != "" {
+		return nil, false
+	}
+	return &data, true
+}
@@ -0,0 +1,62 @@
+package indexclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/Joxx0r/UnrealClaudeFileHelper/internal/indexcache"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc, cacheTTL time.Duration) *Client {
+	t.Helper()
+	ts := httptest.NewServer(handler)
+	t.Cleanup(ts.Close)
+	t.Setenv("UNREAL_CLAUDE_CACHE_DIR", t.TempDir())
+
+	return &Client{
+		BaseURL:        ts.URL,
+		PerCallTimeout: time.Second,
+		http:           ts.Client(),
+		cache:          indexcache.New(cacheTTL),
+	}
+}
+
+func TestEnsureVersionCheckedSkippedWhenCacheEmpty(t *testing.T) {
+	var probes int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.Write([]byte(`{"buildId":"abc"}`))
+	}, time.Minute)
+
+	c.ensureVersionChecked(context.Background())
+	if got := atomic.LoadInt32(&probes); got != 0 {
+		t.Errorf("version probe fired %d times, want 0 for an empty cache", got)
+	}
+}
+
+func TestEnsureVersionCheckedRunsOncePerProcess(t *testing.T) {
+	var probes int32
+	c := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&probes, 1)
+		w.Write([]byte(`{"buildId":"abc"}`))
+	}, time.Minute)
+
+	params := url.Values{}
+	params.Set("pattern", "Tick")
+	c.cache.Put("/grep", params, json.RawMessage(`{"results":[]}`), "Tick", false)
+
+	ctx := context.Background()
+	c.ensureVersionChecked(ctx)
+	c.ensureVersionChecked(ctx)
+	c.ensureVersionChecked(ctx)
+
+	if got := atomic.LoadInt32(&probes); got != 1 {
+		t.Errorf("version probe fired %d times, want exactly 1 per process", got)
+	}
+}
@@ -0,0 +1,376 @@
+// Package lsp is a minimal Language Server Protocol server over
+// stdio, translating a handful of LSP requests into the same
+// serviceURL calls the Claude Code hook uses, so the index is usable
+// from any LSP-speaking editor (VSCode, Neovim, Emacs, ...).
+package lsp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Joxx0r/UnrealClaudeFileHelper/internal/indexclient"
+)
+
+const serviceURL = "http://127.0.0.1:3847"
+const perCallTimeout = 2 * time.Second
+
+// Run starts the LSP server, reading requests from stdin and writing
+// responses to stdout until the client sends "exit".
+func Run() {
+	s := &server{
+		client: indexclient.New(serviceURL, perCallTimeout),
+		in:     bufio.NewReader(os.Stdin),
+		out:    os.Stdout,
+	}
+	s.loop()
+}
+
+type server struct {
+	client *indexclient.Client
+	in     *bufio.Reader
+	out    io.Writer
+}
+
+// ── JSON-RPC framing ─────────────────────────────────────────
+
+type rpcRequest struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      interface{}     `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type rpcResponse struct {
+	JSONRPC string      `json:"jsonrpc"`
+	ID      interface{} `json:"id"`
+	Result  interface{} `json:"result,omitempty"`
+	Error   *rpcError   `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+func (s *server) readMessage() (*rpcRequest, error) {
+	var contentLength int
+	for {
+		line, err := s.in.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		if strings.HasPrefix(line, "Content-Length:") {
+			n, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(line, "Content-Length:")))
+			if err != nil {
+				return nil, err
+			}
+			contentLength = n
+		}
+	}
+	body := make([]byte, contentLength)
+	if _, err := io.ReadFull(s.in, body); err != nil {
+		return nil, err
+	}
+	var req rpcRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		return nil, err
+	}
+	return &req, nil
+}
+
+func (s *server) writeMessage(v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+	fmt.Fprintf(s.out, "Content-Length: %d\r\n\r\n%s", len(body), body)
+}
+
+func (s *server) reply(id interface{}, result interface{}) {
+	s.writeMessage(rpcResponse{JSONRPC: "2.0", ID: id, Result: result})
+}
+
+func (s *server) replyError(id interface{}, code int, message string) {
+	s.writeMessage(rpcResponse{JSONRPC: "2.0", ID: id, Error: &rpcError{Code: code, Message: message}})
+}
+
+// ── LSP protocol types (the subset this server needs) ────────
+
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+type TextDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type TextDocumentPositionParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+type ReferenceParams struct {
+	TextDocumentPositionParams
+}
+
+type WorkspaceSymbolParams struct {
+	Query string `json:"query"`
+}
+
+type DocumentSymbolParams struct {
+	TextDocument TextDocumentIdentifier `json:"textDocument"`
+}
+
+// SymbolKind values from the LSP spec — only the ones this server emits.
+const (
+	symbolKindClass    = 5
+	symbolKindMethod   = 6
+	symbolKindFunction = 12
+)
+
+type SymbolInformation struct {
+	Name     string   `json:"name"`
+	Kind     int      `json:"kind"`
+	Location Location `json:"location"`
+}
+
+// ── Main loop ──────────────────────────────────────────────────
+
+func (s *server) loop() {
+	for {
+		req, err := s.readMessage()
+		if err != nil {
+			return
+		}
+		switch req.Method {
+		case "initialize":
+			s.handleInitialize(req)
+		case "initialized":
+			// notification, no response
+		case "shutdown":
+			s.reply(req.ID, nil)
+		case "exit":
+			os.Exit(0)
+		case "textDocument/definition":
+			s.handleDefinition(req)
+		case "textDocument/references":
+			s.handleReferences(req)
+		case "workspace/symbol":
+			s.handleWorkspaceSymbol(req)
+		case "textDocument/documentSymbol":
+			s.handleDocumentSymbol(req)
+		default:
+			if req.ID != nil {
+				s.replyError(req.ID, -32601, "method not found: "+req.Method)
+			}
+		}
+	}
+}
+
+func (s *server) handleInitialize(req *rpcRequest) {
+	s.reply(req.ID, map[string]interface{}{
+		"capabilities": map[string]interface{}{
+			"definitionProvider":      true,
+			"referencesProvider":      true,
+			"workspaceSymbolProvider": true,
+			"documentSymbolProvider":  true,
+		},
+	})
+}
+
+// ── textDocument/definition ──────────────────────────────────
+
+func (s *server) handleDefinition(req *rpcRequest) {
+	var params TextDocumentPositionParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.replyError(req.ID, -32602, "invalid params")
+		return
+	}
+
+	path := uriToPath(params.TextDocument.URI)
+	symbol, ok := wordAt(path, params.Position)
+	if !ok {
+		s.reply(req.ID, nil)
+		return
+	}
+
+	ctx := context.Background()
+	if data, ok := s.client.FindType(ctx, symbol, 1); ok && len(data.Results) > 0 {
+		r := data.Results[0]
+		s.reply(req.ID, []Location{{URI: pathToURI(r.Path), Range: lineRange(r.Line)}})
+		return
+	}
+	if data, ok := s.client.FindMember(ctx, symbol, 1); ok && len(data.Results) > 0 {
+		r := data.Results[0]
+		s.reply(req.ID, []Location{{URI: pathToURI(r.Path), Range: lineRange(r.Line)}})
+		return
+	}
+	s.reply(req.ID, nil)
+}
+
+// ── textDocument/references ──────────────────────────────────
+
+func (s *server) handleReferences(req *rpcRequest) {
+	var params ReferenceParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.replyError(req.ID, -32602, "invalid params")
+		return
+	}
+
+	path := uriToPath(params.TextDocument.URI)
+	symbol, ok := wordAt(path, params.Position)
+	if !ok {
+		s.reply(req.ID, nil)
+		return
+	}
+
+	query := url.Values{}
+	query.Set("pattern", symbol)
+	query.Set("symbols", "true")
+	data, ok := s.client.Grep(context.Background(), query)
+	if !ok {
+		s.reply(req.ID, nil)
+		return
+	}
+
+	locations := make([]Location, 0, len(data.Results))
+	for _, r := range data.Results {
+		locations = append(locations, Location{URI: pathToURI(r.File), Range: lineRange(r.Line)})
+	}
+	s.reply(req.ID, locations)
+}
+
+// ── workspace/symbol ──────────────────────────────────────────
+
+func (s *server) handleWorkspaceSymbol(req *rpcRequest) {
+	var params WorkspaceSymbolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.replyError(req.ID, -32602, "invalid params")
+		return
+	}
+
+	ctx := context.Background()
+	var symbols []SymbolInformation
+	if data, ok := s.client.FindType(ctx, params.Query, 50); ok {
+		for _, r := range data.Results {
+			symbols = append(symbols, SymbolInformation{
+				Name:     r.Name,
+				Kind:     symbolKindClass,
+				Location: Location{URI: pathToURI(r.Path), Range: lineRange(r.Line)},
+			})
+		}
+	}
+	if data, ok := s.client.FindMember(ctx, params.Query, 50); ok {
+		for _, r := range data.Results {
+			kind := symbolKindMethod
+			if r.OwnerName == "" {
+				kind = symbolKindFunction
+			}
+			symbols = append(symbols, SymbolInformation{
+				Name:     r.Name,
+				Kind:     kind,
+				Location: Location{URI: pathToURI(r.Path), Range: lineRange(r.Line)},
+			})
+		}
+	}
+	s.reply(req.ID, symbols)
+}
+
+// ── textDocument/documentSymbol ───────────────────────────────
+
+func (s *server) handleDocumentSymbol(req *rpcRequest) {
+	var params DocumentSymbolParams
+	if err := json.Unmarshal(req.Params, &params); err != nil {
+		s.replyError(req.ID, -32602, "invalid params")
+		return
+	}
+
+	// TODO: /file-symbols isn't served by the index yet — this call
+	// is wired up so documentSymbol starts working the moment it is.
+	data, ok := s.client.FileSymbols(context.Background(), uriToPath(params.TextDocument.URI))
+	if !ok {
+		s.reply(req.ID, nil)
+		return
+	}
+
+	symbols := make([]SymbolInformation, 0, len(data.Results))
+	for _, r := range data.Results {
+		kind := symbolKindFunction
+		if r.Kind == "class" || r.Kind == "struct" || r.Kind == "enum" {
+			kind = symbolKindClass
+		}
+		symbols = append(symbols, SymbolInformation{
+			Name:     r.Name,
+			Kind:     kind,
+			Location: Location{URI: params.TextDocument.URI, Range: lineRange(r.Line)},
+		})
+	}
+	s.reply(req.ID, symbols)
+}
+
+// ── Helpers ──────────────────────────────────────────────────
+
+var identRe = regexp.MustCompile(`\w+`)
+
+func uriToPath(uri string) string {
+	return strings.TrimPrefix(uri, "file://")
+}
+
+func pathToURI(path string) string {
+	if strings.HasPrefix(path, "file://") {
+		return path
+	}
+	return "file://" + path
+}
+
+func lineRange(line int) Range {
+	// Index lines are 1-based; LSP positions are 0-based.
+	l := line - 1
+	if l < 0 {
+		l = 0
+	}
+	return Range{Start: Position{Line: l, Character: 0}, End: Position{Line: l, Character: 0}}
+}
+
+// wordAt reads path and returns the identifier under pos.
+func wordAt(path string, pos Position) (string, bool) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	lines := strings.Split(string(data), "\n")
+	if pos.Line < 0 || pos.Line >= len(lines) {
+		return "", false
+	}
+	line := lines[pos.Line]
+	for _, loc := range identRe.FindAllStringIndex(line, -1) {
+		if pos.Character >= loc[0] && pos.Character <= loc[1] {
+			return line[loc[0]:loc[1]], true
+		}
+	}
+	return "", false
+}
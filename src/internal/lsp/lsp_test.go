@@ -0,0 +1,122 @@
+package lsp
+
+import (
+	"bufio"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestURIToPath(t *testing.T) {
+	cases := []struct {
+		uri  string
+		want string
+	}{
+		{"file:///home/user/Foo.cpp", "/home/user/Foo.cpp"},
+		{"/already/a/path", "/already/a/path"},
+	}
+	for _, c := range cases {
+		if got := uriToPath(c.uri); got != c.want {
+			t.Errorf("uriToPath(%q) = %q, want %q", c.uri, got, c.want)
+		}
+	}
+}
+
+func TestPathToURI(t *testing.T) {
+	cases := []struct {
+		path string
+		want string
+	}{
+		{"/home/user/Foo.cpp", "file:///home/user/Foo.cpp"},
+		{"file:///already/a/uri", "file:///already/a/uri"},
+	}
+	for _, c := range cases {
+		if got := pathToURI(c.path); got != c.want {
+			t.Errorf("pathToURI(%q) = %q, want %q", c.path, got, c.want)
+		}
+	}
+}
+
+func TestURIPathRoundTrip(t *testing.T) {
+	path := "/home/user/Foo.cpp"
+	if got := uriToPath(pathToURI(path)); got != path {
+		t.Errorf("uriToPath(pathToURI(%q)) = %q, want %q", path, got, path)
+	}
+}
+
+func TestLineRange(t *testing.T) {
+	cases := []struct {
+		line int
+		want int
+	}{
+		{1, 0},
+		{5, 4},
+		{0, 0},
+	}
+	for _, c := range cases {
+		r := lineRange(c.line)
+		if r.Start.Line != c.want || r.End.Line != c.want {
+			t.Errorf("lineRange(%d) = %+v, want Line %d", c.line, r, c.want)
+		}
+	}
+}
+
+func TestWordAt(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "Foo.cpp")
+	content := "void FTickFunction() {\n  return;\n}\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name      string
+		pos       Position
+		wantWord  string
+		wantFound bool
+	}{
+		{"inside identifier", Position{Line: 0, Character: 7}, "FTickFunction", true},
+		{"second line identifier", Position{Line: 1, Character: 2}, "return", true},
+		{"line out of range", Position{Line: 50, Character: 0}, "", false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			word, ok := wordAt(path, c.pos)
+			if ok != c.wantFound {
+				t.Fatalf("wordAt() ok = %v, want %v", ok, c.wantFound)
+			}
+			if ok && word != c.wantWord {
+				t.Errorf("wordAt() = %q, want %q", word, c.wantWord)
+			}
+		})
+	}
+}
+
+func TestWordAtMissingFile(t *testing.T) {
+	if _, ok := wordAt(filepath.Join(t.TempDir(), "missing.cpp"), Position{}); ok {
+		t.Error("wordAt() on a missing file should report not found")
+	}
+}
+
+func TestReadMessage(t *testing.T) {
+	body := `{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`
+	raw := "Content-Length: " + strconv.Itoa(len(body)) + "\r\n\r\n" + body
+	s := &server{in: bufio.NewReader(strings.NewReader(raw))}
+
+	req, err := s.readMessage()
+	if err != nil {
+		t.Fatalf("readMessage() error = %v", err)
+	}
+	if req.Method != "initialize" {
+		t.Errorf("req.Method = %q, want %q", req.Method, "initialize")
+	}
+}
+
+func TestReadMessageEOF(t *testing.T) {
+	s := &server{in: bufio.NewReader(strings.NewReader(""))}
+	if _, err := s.readMessage(); err == nil {
+		t.Error("readMessage() on an empty stream should return an error")
+	}
+}